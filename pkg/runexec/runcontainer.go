@@ -0,0 +1,216 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runexec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/oci"
+)
+
+// imageScheme is the app path prefix that selects the container runtime
+// backend, e.g. "image://docker.io/library/myapp:latest".
+const imageScheme = "image://"
+
+// containerdNamespace isolates the containers dapr run creates from anything
+// else running on the same containerd (or rootless Podman) socket.
+const containerdNamespace = "dapr-cli"
+
+const defaultContainerdAddress = "/run/containerd/containerd.sock"
+
+// isImageRef reports whether path names an OCI image rather than a local
+// executable or wasm binary.
+func isImageRef(path string) bool {
+	return strings.HasPrefix(path, imageScheme)
+}
+
+// containerdAddress returns the containerd (or Podman) socket to dial,
+// honoring CONTAINERD_ADDRESS so rootless setups can point at their own
+// socket without code changes.
+func containerdAddress() string {
+	if addr := os.Getenv("CONTAINERD_ADDRESS"); addr != "" {
+		return addr
+	}
+	return defaultContainerdAddress
+}
+
+// NewContainerCmd returns a RunnableCmd that runs image inside a container
+// via the containerd client connected at addr.
+func NewContainerCmd(addr, image string, args []string, env []string) (RunnableCmd, error) {
+	client, err := containerd.New(addr)
+	if err != nil {
+		return nil, fmt.Errorf("containerd: error connecting to %s: %w", addr, err)
+	}
+
+	errR, errW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	return &ContainerRunnableCmd{
+		client:       client,
+		image:        image,
+		args:         args,
+		env:          env,
+		stderrReader: errR,
+		stderrWriter: errW,
+		stdoutReader: outR,
+		stdoutWriter: outW,
+		wait:         make(chan bool),
+	}, nil
+}
+
+// ContainerRunnableCmd runs the app in a container managed by containerd,
+// alongside RunnableExecCmd and WasmRunnableCmd.
+type ContainerRunnableCmd struct {
+	client *containerd.Client
+	image  string
+	args   []string
+	env    []string
+
+	ctx       context.Context
+	container containerd.Container
+	task      containerd.Task
+
+	stderrReader io.ReadCloser
+	stderrWriter io.WriteCloser
+	stdoutReader io.ReadCloser
+	stdoutWriter io.WriteCloser
+	running      atomic.Bool
+	exitErr      error
+	wait         chan bool
+}
+
+func (c *ContainerRunnableCmd) StderrPipe() (io.ReadCloser, error) {
+	return c.stderrReader, nil
+}
+
+func (c *ContainerRunnableCmd) StdoutPipe() (io.ReadCloser, error) {
+	return c.stdoutReader, nil
+}
+
+func (c *ContainerRunnableCmd) HasProcess() bool {
+	return c.task != nil
+}
+
+func (c *ContainerRunnableCmd) Pid() int {
+	if c.task == nil {
+		return -1
+	}
+	return int(c.task.Pid())
+}
+
+func (c *ContainerRunnableCmd) Running() bool {
+	return c.running.Load()
+}
+
+func (c *ContainerRunnableCmd) Wait() error {
+	if c.running.Load() {
+		<-c.wait
+	}
+	return c.exitErr
+}
+
+// Kill asks the task to shut down gracefully before forcing it down, mirroring
+// the two-stage stop dapr run already does for the exec backend.
+func (c *ContainerRunnableCmd) Kill() error {
+	if c.task == nil {
+		return nil
+	}
+	if err := c.task.Kill(c.ctx, syscall.SIGTERM); err != nil {
+		return c.task.Kill(c.ctx, syscall.SIGKILL)
+	}
+	return nil
+}
+
+func (c *ContainerRunnableCmd) Start() error {
+	c.ctx = namespaces.WithNamespace(context.Background(), containerdNamespace)
+
+	image, err := c.client.Pull(c.ctx, c.image, containerd.WithPullUnpack)
+	if err != nil {
+		return fmt.Errorf("containerd: error pulling %s: %w", c.image, err)
+	}
+
+	id := containerID(c.image)
+	container, err := c.client.NewContainer(
+		c.ctx,
+		id,
+		containerd.WithNewSnapshot(id+"-snapshot", image),
+		containerd.WithNewSpec(oci.WithImageConfig(image), oci.WithProcessArgs(c.args...), oci.WithEnv(c.env)),
+	)
+	if err != nil {
+		return fmt.Errorf("containerd: error creating container: %w", err)
+	}
+	c.container = container
+
+	task, err := container.NewTask(c.ctx, cio.NewCreator(cio.WithStreams(nil, c.stdoutWriter, c.stderrWriter)))
+	if err != nil {
+		return fmt.Errorf("containerd: error creating task: %w", err)
+	}
+	c.task = task
+
+	exitCh, err := task.Wait(c.ctx)
+	if err != nil {
+		return fmt.Errorf("containerd: error waiting on task: %w", err)
+	}
+
+	if err := task.Start(c.ctx); err != nil {
+		return fmt.Errorf("containerd: error starting task: %w", err)
+	}
+	c.running.Store(true)
+
+	go func() {
+		status := <-exitCh
+		c.running.Store(false)
+
+		// Reap the task and its snapshot so the next dapr run against the
+		// same image doesn't collide with a leftover "already exists".
+		if _, err := task.Delete(c.ctx, containerd.WithProcessKill); err != nil {
+			fmt.Println(err.Error())
+		}
+		if err := container.Delete(c.ctx, containerd.WithSnapshotCleanup); err != nil {
+			fmt.Println(err.Error())
+		}
+
+		_ = c.stdoutWriter.Close()
+		_ = c.stderrWriter.Close()
+
+		if code := status.ExitCode(); code != 0 {
+			c.exitErr = fmt.Errorf("containerd: task exited with code %d", code)
+		}
+
+		c.wait <- true
+	}()
+
+	return nil
+}
+
+// containerSeq disambiguates container IDs within a single process so that
+// concurrent or back-to-back runs against the same image never collide,
+// even if the previous invocation's cleanup hasn't completed yet.
+var containerSeq uint64
+
+// containerID derives a containerd container ID from an image reference,
+// since containerd IDs cannot contain "/" or ":".
+func containerID(image string) string {
+	seq := atomic.AddUint64(&containerSeq, 1)
+	return fmt.Sprintf("dapr-run-%s-%d", strings.NewReplacer("/", "-", ":", "-").Replace(image), seq)
+}
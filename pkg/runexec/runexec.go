@@ -66,7 +66,7 @@ func (r *RunnableExecCmd) Running() bool {
 }
 
 func (r *RunnableExecCmd) Kill() error {
-	return r.Process.Kill()
+	return killProcess(r.Cmd)
 }
 
 // RunOutput represents the run execution.
@@ -152,8 +152,13 @@ func NewOutput(config *standalone.RunConfig) (*RunOutput, error) {
 	//nolint
 	var appCMD *exec.Cmd = standalone.GetAppCommand(config)
 	var execCmd RunnableCmd
-	if appCMD != nil && strings.HasSuffix(appCMD.Path, ".wasm") {
-		execCmd, err = NewWasmCmd(appCMD.Path, appCMD.Args, appCMD.Env)
+	if appCMD != nil && isImageRef(appCMD.Path) {
+		execCmd, err = NewContainerCmd(containerdAddress(), strings.TrimPrefix(appCMD.Path, imageScheme), appCMD.Args, appCMD.Env)
+		if err != nil {
+			return nil, err
+		}
+	} else if appCMD != nil && (strings.HasSuffix(appCMD.Path, ".wasm") || isOCIRef(appCMD.Path)) {
+		execCmd, err = NewWasmCmd(appCMD.Path, appCMD.Args, appCMD.Env, config.WasmMounts, config.WasmAllowNet)
 		if err != nil {
 			return nil, err
 		}
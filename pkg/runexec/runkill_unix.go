@@ -0,0 +1,32 @@
+//go:build !windows
+// +build !windows
+
+package runexec
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// killGracePeriod is how long killProcess waits between signals for the
+// process to exit on its own before escalating.
+const killGracePeriod = 100 * time.Millisecond
+
+// killProcess asks cmd's process to stop with SIGINT, giving it a beat to
+// exit cleanly, then sends a second SIGINT the way a shell's own "hit
+// Ctrl-C twice to force it" convention does, and finally falls back to an
+// unconditional Kill if it's still around.
+func killProcess(cmd *exec.Cmd) error {
+	if err := cmd.Process.Signal(syscall.SIGINT); err != nil {
+		return cmd.Process.Kill()
+	}
+	time.Sleep(killGracePeriod)
+
+	if err := cmd.Process.Signal(syscall.SIGINT); err != nil {
+		return nil
+	}
+	time.Sleep(killGracePeriod)
+
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,16 @@
+//go:build windows
+// +build windows
+
+package runexec
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// killProcess shells out to taskkill rather than cmd.Process.Kill alone:
+// Windows has no SIGINT to ask a process to exit cleanly, and /T also tears
+// down any children the process spawned, which Process.Kill does not.
+func killProcess(cmd *exec.Cmd) error {
+	return exec.Command("taskkill", "/F", "/T", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
@@ -5,13 +5,71 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 
+	daprc "github.com/dapr/go-sdk/client"
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/sock"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 )
 
+var (
+	sharedCompilationCache     wazero.CompilationCache
+	sharedCompilationCacheErr  error
+	sharedCompilationCacheOnce sync.Once
+)
+
+// getSharedCompilationCache lazily opens the on-disk compilation cache shared
+// by every WasmRunnableCmd in the process, so `dapr run` invocations that fan
+// out multiple wasm apps (or simply restart) skip recompiling modules
+// they've already seen. Each WasmRunnableCmd still gets its own
+// wazero.Runtime -- the cache is the only thing shared -- so two guests that
+// both import env.dapr each get their own host-module namespace instead of
+// colliding on the same registered name.
+func getSharedCompilationCache() (wazero.CompilationCache, error) {
+	sharedCompilationCacheOnce.Do(func() {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			sharedCompilationCacheErr = err
+			return
+		}
+
+		sharedCompilationCache, sharedCompilationCacheErr = wazero.NewCompilationCacheWithDir(filepath.Join(userCacheDir, "dapr", "wazero"))
+		if sharedCompilationCacheErr != nil {
+			sharedCompilationCacheErr = fmt.Errorf("wasm: error opening compilation cache: %w", sharedCompilationCacheErr)
+		}
+	})
+	return sharedCompilationCache, sharedCompilationCacheErr
+}
+
+// wasmMount is a parsed "--wasm-mount host:guest" pair that gets preopened
+// into the guest's WASI filesystem.
+type wasmMount struct {
+	host  string
+	guest string
+}
+
+// parseWasmMounts parses "host:guest" pairs and validates that each host path
+// exists, failing fast the same way the env var parsing in Start does.
+func parseWasmMounts(mounts []string) ([]wasmMount, error) {
+	parsed := make([]wasmMount, 0, len(mounts))
+	for _, m := range mounts {
+		parts := strings.SplitN(m, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("unexpected wasm mount: %s", m)
+		}
+		if _, err := os.Stat(parts[0]); err != nil {
+			return nil, fmt.Errorf("wasm mount %s: %w", m, err)
+		}
+		parsed = append(parsed, wasmMount{host: parts[0], guest: parts[1]})
+	}
+	return parsed, nil
+}
+
 func detectWasi(imports []api.FunctionDefinition) bool {
 	for _, f := range imports {
 		moduleName, _, _ := f.Import()
@@ -22,7 +80,15 @@ func detectWasi(imports []api.FunctionDefinition) bool {
 	return false
 }
 
-func NewWasmCmd(path string, args []string, env []string) (RunnableCmd, error) {
+func NewWasmCmd(path string, args []string, env []string, mounts []string, allowNet bool) (RunnableCmd, error) {
+	if isOCIRef(path) {
+		cached, err := pullWasmModule(context.Background(), path)
+		if err != nil {
+			return nil, err
+		}
+		path = cached
+	}
+
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -31,35 +97,43 @@ func NewWasmCmd(path string, args []string, env []string) (RunnableCmd, error) {
 	if err != nil {
 		return nil, err
 	}
+	parsedMounts, err := parseWasmMounts(mounts)
+	if err != nil {
+		return nil, err
+	}
 	errR, errW := io.Pipe()
 	outR, outW := io.Pipe()
 	return &WasmRunnableCmd{
 		wasm:         data,
 		args:         args,
 		env:          env,
+		mounts:       parsedMounts,
+		allowNet:     allowNet,
 		stderrReader: errR,
 		stderrWriter: errW,
 		stdoutReader: outR,
 		stdoutWriter: outW,
-		running:      false,
 		wait:         make(chan bool),
 	}, nil
 }
 
 type WasmRunnableCmd struct {
-	wasm []byte
-	args []string
-	env  []string
+	wasm     []byte
+	args     []string
+	env      []string
+	mounts   []wasmMount
+	allowNet bool
 
-	runtime wazero.Runtime
-	module  wazero.CompiledModule
-	ctx     context.Context
+	runtime  wazero.Runtime
+	module   wazero.CompiledModule
+	instance api.Module
+	ctx      context.Context
 
 	stderrReader io.ReadCloser
 	stderrWriter io.WriteCloser
 	stdoutReader io.ReadCloser
 	stdoutWriter io.WriteCloser
-	running      bool
+	running      atomic.Bool
 	wait         chan bool
 }
 
@@ -80,49 +154,82 @@ func (w *WasmRunnableCmd) Pid() int {
 }
 
 func (w *WasmRunnableCmd) Running() bool {
-	return w.running
+	return w.running.Load()
 }
 
 func (w *WasmRunnableCmd) Wait() error {
-	if w.running {
+	if w.running.Load() {
 		<-w.wait
 	}
 	return nil
 }
 
+// Kill closes this instance's own runtime, which wazero turns into an
+// interrupt of whatever guest code is currently executing. Since Start gives
+// each WasmRunnableCmd its own runtime, this never affects any other
+// concurrently running wasm app.
 func (w *WasmRunnableCmd) Kill() error {
-	return w.runtime.Close(w.ctx)
+	if w.runtime != nil {
+		return w.runtime.Close(w.ctx)
+	}
+	return nil
 }
 
 func (w *WasmRunnableCmd) Start() error {
 	var err error
-	config := wazero.NewRuntimeConfig()
-	w.ctx = context.TODO()
+	w.ctx = context.Background()
 
-	// Create the runtime, which when closed releases any resources associated with it.
+	compilationCache, err := getSharedCompilationCache()
+	if err != nil {
+		return err
+	}
+
+	// Each WasmRunnableCmd gets its own runtime -- only the compiled-module
+	// cache is shared -- so registering env.dapr below never collides with
+	// another guest's host module.
+	config := wazero.NewRuntimeConfig().WithCompilationCache(compilationCache)
 	w.runtime = wazero.NewRuntimeWithConfig(w.ctx, config)
 
-	// Compile the module, which reduces execution time of Invoke
+	if _, err := wasi_snapshot_preview1.Instantiate(w.ctx, w.runtime); err != nil {
+		return fmt.Errorf("wasm: error instantiating host functions: %w", err)
+	}
+
+	// Compile the module. The shared compilation cache means this is a no-op
+	// the second time any process on the machine runs this module.
 	w.module, err = w.runtime.CompileModule(w.ctx, w.wasm)
 	if err != nil {
-		_ = w.runtime.Close(context.Background())
 		return fmt.Errorf("wasm: error compiling binary: %w", err)
 	}
 
-	if detectWasi(w.module.ImportedFunctions()) {
-		_, err = wasi_snapshot_preview1.Instantiate(w.ctx, w.runtime)
-
+	var daprClient daprc.Client
+	if detectDaprHost(w.module.ImportedFunctions()) {
+		daprClient, err = newDaprClientFromEnv(w.env)
 		if err != nil {
-			_ = w.runtime.Close(context.Background())
-			return fmt.Errorf("wasm: error instantiating host functions: %w", err)
+			return err
 		}
 	}
 
+	if w.allowNet {
+		sockConfig := sock.NewConfig()
+		for _, port := range daprSidecarPorts(w.env) {
+			sockConfig = sockConfig.WithTCPListener("127.0.0.1", port)
+		}
+		w.ctx = sock.WithConfig(w.ctx, sockConfig)
+	}
+
 	moduleConfig := wazero.NewModuleConfig().
 		WithStderr(w.stderrWriter).
 		WithStdout(w.stdoutWriter).
 		WithArgs(w.args...)
 
+	if len(w.mounts) > 0 {
+		fsConfig := wazero.NewFSConfig()
+		for _, m := range w.mounts {
+			fsConfig = fsConfig.WithDirMount(m.host, m.guest)
+		}
+		moduleConfig = moduleConfig.WithFSConfig(fsConfig)
+	}
+
 	for _, env := range w.env {
 		parts := strings.Split(env, "=")
 		switch len(parts) {
@@ -135,15 +242,58 @@ func (w *WasmRunnableCmd) Start() error {
 		}
 	}
 
+	w.running.Store(true)
+
 	go func() {
+		// registerDaprHost registers env.dapr on this guest's own runtime, so
+		// unlike the old single-shared-runtime design, two guests that both
+		// import env.dapr never contend for the same registered name and run
+		// fully concurrently.
+		var daprHost api.Closer
+		if daprClient != nil {
+			closer, err := registerDaprHost(w.ctx, w.runtime, daprClient, w.stderrWriter)
+			if err != nil {
+				fmt.Fprintf(w.stderrWriter, "wasm: error registering %s host functions: %s\n", daprHostModuleName, err.Error())
+			} else {
+				daprHost = closer
+			}
+		}
+
 		mod, err := w.runtime.InstantiateModule(w.ctx, w.module, moduleConfig)
 		if err != nil {
 			fmt.Println(err.Error())
 		}
+		w.instance = mod
 		_ = mod.Close(w.ctx)
 
+		if daprHost != nil {
+			_ = daprHost.Close(w.ctx)
+		}
+
+		// Each WasmRunnableCmd owns its runtime exclusively, so it's safe (and
+		// necessary, since nothing else references it) to close it here.
+		_ = w.runtime.Close(w.ctx)
+
+		_ = w.stdoutWriter.Close()
+		_ = w.stderrWriter.Close()
+
+		w.running.Store(false)
 		w.wait <- true
 	}()
 
 	return nil
 }
+
+// daprSidecarPorts pulls DAPR_HTTP_PORT and DAPR_GRPC_PORT out of the guest
+// env so the experimental sockets extension can be scoped to just the
+// sidecar's ports rather than opening the guest up to the whole loopback
+// range.
+func daprSidecarPorts(env []string) []int {
+	var ports []int
+	for _, key := range []string{"DAPR_HTTP_PORT", "DAPR_GRPC_PORT"} {
+		if port, ok := envPort(env, key); ok {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
@@ -0,0 +1,220 @@
+package runexec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	daprc "github.com/dapr/go-sdk/client"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// daprHostModuleName is the import module name a wasm guest uses to call
+// back into the Dapr sidecar, e.g. (import "env.dapr" "get_state" ...).
+const daprHostModuleName = "env.dapr"
+
+// detectDaprHost mirrors detectWasi: it reports whether the module imports
+// the env.dapr host functions, so plain WASI binaries that don't need a
+// sidecar are instantiated unchanged.
+func detectDaprHost(imports []api.FunctionDefinition) bool {
+	for _, f := range imports {
+		moduleName, _, _ := f.Import()
+		if moduleName == daprHostModuleName {
+			return true
+		}
+	}
+	return false
+}
+
+func envPort(env []string, key string) (int, bool) {
+	for _, e := range env {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) != 2 || parts[0] != key {
+			continue
+		}
+		if port, err := strconv.Atoi(parts[1]); err == nil {
+			return port, true
+		}
+	}
+	return 0, false
+}
+
+func newDaprClientFromEnv(env []string) (daprc.Client, error) {
+	port, ok := envPort(env, "DAPR_GRPC_PORT")
+	if !ok {
+		return nil, fmt.Errorf("wasm: DAPR_GRPC_PORT not set for env.dapr host functions")
+	}
+	return daprc.NewClientWithAddress(fmt.Sprintf("127.0.0.1:%d", port))
+}
+
+// readGuestBytes copies length bytes out of the guest's linear memory
+// starting at ptr, the same offset+length convention every env.dapr host
+// function uses for its arguments.
+func readGuestBytes(mod api.Module, ptr, length uint32) ([]byte, error) {
+	buf, ok := mod.Memory().Read(ptr, length)
+	if !ok {
+		return nil, fmt.Errorf("wasm: out-of-bounds guest memory read at %d+%d", ptr, length)
+	}
+	out := make([]byte, len(buf))
+	copy(out, buf)
+	return out, nil
+}
+
+// writeGuestResponse has the guest allocate a buffer via its exported
+// alloc(size) -> ptr function and copies data into it, returning the
+// (ptr, length) pair the host function hands back to the guest.
+func writeGuestResponse(ctx context.Context, mod api.Module, data []byte) (uint32, uint32, error) {
+	alloc := mod.ExportedFunction("alloc")
+	if alloc == nil {
+		return 0, 0, fmt.Errorf("wasm: guest does not export alloc(size) -> ptr")
+	}
+	results, err := alloc.Call(ctx, uint64(len(data)))
+	if err != nil {
+		return 0, 0, fmt.Errorf("wasm: error calling guest alloc: %w", err)
+	}
+	ptr := uint32(results[0])
+	if !mod.Memory().Write(ptr, data) {
+		return 0, 0, fmt.Errorf("wasm: out-of-bounds guest memory write at %d+%d", ptr, len(data))
+	}
+	return ptr, uint32(len(data)), nil
+}
+
+// daprHostServer holds the state every env.dapr host function closes over:
+// the SDK client used to reach the sidecar, and the app's own stderr pipe,
+// where host-call failures get reported instead of the CLI process's stdout.
+type daprHostServer struct {
+	client daprc.Client
+	errW   io.Writer
+}
+
+func (h *daprHostServer) logError(fn string, err error) {
+	fmt.Fprintf(h.errW, "wasm: env.dapr.%s: %s\n", fn, err.Error())
+}
+
+// registerDaprHost wires the env.dapr host module into runtime, backing
+// invoke_service/publish_event/get_state/save_state with c. Each function
+// reads its string/byte arguments out of guest memory and writes its
+// response back into a guest-allocated buffer. Failures are reported on
+// errW (the app's own stderr) rather than the CLI's own stdout.
+func registerDaprHost(ctx context.Context, runtime wazero.Runtime, c daprc.Client, errW io.Writer) (api.Closer, error) {
+	h := &daprHostServer{client: c, errW: errW}
+
+	return runtime.NewHostModuleBuilder(daprHostModuleName).
+		NewFunctionBuilder().WithFunc(h.invokeService).Export("invoke_service").
+		NewFunctionBuilder().WithFunc(h.publishEvent).Export("publish_event").
+		NewFunctionBuilder().WithFunc(h.getState).Export("get_state").
+		NewFunctionBuilder().WithFunc(h.saveState).Export("save_state").
+		Instantiate(ctx)
+}
+
+// invokeService and getState return (ok, ptr, len): ok distinguishes a failed
+// host call from a successful one that legitimately produced an empty
+// result, which (ptr, len) == (0, 0) alone cannot.
+func (h *daprHostServer) invokeService(ctx context.Context, mod api.Module, appIDPtr, appIDLen, methodPtr, methodLen, dataPtr, dataLen uint32) (ok, ptr, length uint32) {
+	appID, err := readGuestBytes(mod, appIDPtr, appIDLen)
+	if err != nil {
+		h.logError("invoke_service", err)
+		return 0, 0, 0
+	}
+	method, err := readGuestBytes(mod, methodPtr, methodLen)
+	if err != nil {
+		h.logError("invoke_service", err)
+		return 0, 0, 0
+	}
+	data, err := readGuestBytes(mod, dataPtr, dataLen)
+	if err != nil {
+		h.logError("invoke_service", err)
+		return 0, 0, 0
+	}
+
+	content := &daprc.DataContent{Data: data, ContentType: "application/json"}
+	resp, err := h.client.InvokeMethodWithContent(ctx, string(appID), string(method), "post", content)
+	if err != nil {
+		h.logError("invoke_service", err)
+		return 0, 0, 0
+	}
+
+	ptr, length, err = writeGuestResponse(ctx, mod, resp)
+	if err != nil {
+		h.logError("invoke_service", err)
+		return 0, 0, 0
+	}
+	return 1, ptr, length
+}
+
+func (h *daprHostServer) publishEvent(ctx context.Context, mod api.Module, pubsubPtr, pubsubLen, topicPtr, topicLen, dataPtr, dataLen uint32) uint32 {
+	pubsubName, err := readGuestBytes(mod, pubsubPtr, pubsubLen)
+	if err != nil {
+		h.logError("publish_event", err)
+		return 1
+	}
+	topic, err := readGuestBytes(mod, topicPtr, topicLen)
+	if err != nil {
+		h.logError("publish_event", err)
+		return 1
+	}
+	data, err := readGuestBytes(mod, dataPtr, dataLen)
+	if err != nil {
+		h.logError("publish_event", err)
+		return 1
+	}
+
+	if err := h.client.PublishEvent(ctx, string(pubsubName), string(topic), data); err != nil {
+		h.logError("publish_event", err)
+		return 1
+	}
+	return 0
+}
+
+func (h *daprHostServer) getState(ctx context.Context, mod api.Module, storePtr, storeLen, keyPtr, keyLen uint32) (ok, ptr, length uint32) {
+	storeName, err := readGuestBytes(mod, storePtr, storeLen)
+	if err != nil {
+		h.logError("get_state", err)
+		return 0, 0, 0
+	}
+	key, err := readGuestBytes(mod, keyPtr, keyLen)
+	if err != nil {
+		h.logError("get_state", err)
+		return 0, 0, 0
+	}
+
+	item, err := h.client.GetState(ctx, string(storeName), string(key), nil)
+	if err != nil {
+		h.logError("get_state", err)
+		return 0, 0, 0
+	}
+
+	ptr, length, err = writeGuestResponse(ctx, mod, item.Value)
+	if err != nil {
+		h.logError("get_state", err)
+		return 0, 0, 0
+	}
+	return 1, ptr, length
+}
+
+func (h *daprHostServer) saveState(ctx context.Context, mod api.Module, storePtr, storeLen, keyPtr, keyLen, valuePtr, valueLen uint32) uint32 {
+	storeName, err := readGuestBytes(mod, storePtr, storeLen)
+	if err != nil {
+		h.logError("save_state", err)
+		return 1
+	}
+	key, err := readGuestBytes(mod, keyPtr, keyLen)
+	if err != nil {
+		h.logError("save_state", err)
+		return 1
+	}
+	value, err := readGuestBytes(mod, valuePtr, valueLen)
+	if err != nil {
+		h.logError("save_state", err)
+		return 1
+	}
+
+	if err := h.client.SaveState(ctx, string(storeName), string(key), value, nil); err != nil {
+		h.logError("save_state", err)
+		return 1
+	}
+	return 0
+}
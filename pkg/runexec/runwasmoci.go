@@ -0,0 +1,121 @@
+package runexec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+)
+
+// wasmLayerMediaType is the layer media type a wasm module is published
+// under in an OCI registry.
+const wasmLayerMediaType = "application/vnd.wasm.content.layer.v1+wasm"
+
+// isOCIRef reports whether path names a wasm module published to an OCI
+// registry rather than a local file.
+func isOCIRef(path string) bool {
+	return strings.HasPrefix(path, "oci://") || strings.HasPrefix(path, "wasm+oci://")
+}
+
+func trimOCIScheme(ref string) string {
+	ref = strings.TrimPrefix(ref, "wasm+oci://")
+	return strings.TrimPrefix(ref, "oci://")
+}
+
+// wasmCacheDir returns ~/.dapr/wasm-cache, creating it if necessary.
+func wasmCacheDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(home, ".dapr", "wasm-cache")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// pullWasmModule resolves ref (an oci://... or wasm+oci://... reference) to a
+// local file path, downloading it into the wasm cache keyed by digest if it
+// isn't already there so repeated `dapr run` invocations skip the registry.
+func pullWasmModule(ctx context.Context, ref string) (string, error) {
+	repo, err := remote.NewRepository(trimOCIScheme(ref))
+	if err != nil {
+		return "", fmt.Errorf("wasm: error parsing OCI reference %s: %w", ref, err)
+	}
+
+	// Honor DOCKER_CONFIG so private registries authenticate the same way
+	// `docker pull` would.
+	if store, err := credentials.NewStoreFromDocker(credentials.StoreOptions{}); err == nil {
+		repo.Client = &auth.Client{
+			Client:     auth.DefaultClient,
+			Cache:      auth.NewCache(),
+			Credential: credentials.Credential(store),
+		}
+	}
+
+	_, manifestBytes, err := oras.FetchBytes(ctx, repo, repo.Reference.Reference, oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return "", fmt.Errorf("wasm: error resolving manifest for %s: %w", ref, err)
+	}
+
+	var manifest ociv1.Manifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return "", fmt.Errorf("wasm: error decoding manifest for %s: %w", ref, err)
+	}
+
+	var layer *ociv1.Descriptor
+	for i, l := range manifest.Layers {
+		if l.MediaType == wasmLayerMediaType {
+			layer = &manifest.Layers[i]
+			break
+		}
+	}
+	if layer == nil {
+		return "", fmt.Errorf("wasm: %s has no %s layer", ref, wasmLayerMediaType)
+	}
+	// The registry is untrusted input: a malicious or MITM'd one could hand
+	// back a "digest" containing path separators or "..", turning the
+	// filepath.Join below into a write outside wasmCacheDir. Validate() is
+	// the oras/OCI-spec-sanctioned guard against exactly that.
+	if err := layer.Digest.Validate(); err != nil {
+		return "", fmt.Errorf("wasm: invalid layer digest for %s: %w", ref, err)
+	}
+
+	cacheDir, err := wasmCacheDir()
+	if err != nil {
+		return "", err
+	}
+	cachePath := filepath.Join(cacheDir, strings.ReplaceAll(layer.Digest.String(), ":", "-"))
+	if _, err := os.Stat(cachePath); err == nil {
+		return cachePath, nil
+	}
+
+	rc, err := repo.Fetch(ctx, *layer)
+	if err != nil {
+		return "", fmt.Errorf("wasm: error fetching layer %s: %w", layer.Digest, err)
+	}
+	defer rc.Close()
+
+	f, err := os.Create(cachePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, rc); err != nil {
+		os.Remove(cachePath)
+		return "", fmt.Errorf("wasm: error caching layer %s: %w", layer.Digest, err)
+	}
+
+	return cachePath, nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The Dapr Authors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package standalone
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+const (
+	DefaultDaprHTTPPort = 3500
+	DefaultDaprGRPCPort = 50001
+	DefaultMetricsPort  = 9090
+)
+
+// RunConfig is the configuration `dapr run` assembles from its flags before
+// launching daprd and the app it colocates.
+type RunConfig struct {
+	AppID       string   `mapstructure:"app_id"`
+	HTTPPort    int      `mapstructure:"dapr_http_port"`
+	GRPCPort    int      `mapstructure:"dapr_grpc_port"`
+	MetricsPort int      `mapstructure:"metrics_port"`
+	Command     []string `mapstructure:"command"`
+	AppEnv      []string `mapstructure:"app_env"`
+
+	// WasmMounts are "--wasm-mount host:guest" pairs preopened into a wasm
+	// app's WASI filesystem.
+	WasmMounts []string `mapstructure:"wasm_mount"`
+	// WasmAllowNet is "--wasm-allow-net", letting a wasm app connect() out to
+	// the sidecar's HTTP/gRPC ports.
+	WasmAllowNet bool `mapstructure:"wasm_allow_net"`
+}
+
+// SetDefaultFromSchema fills in zero-valued ports with their defaults, the
+// same way the full CLI config schema would have if these flags had been
+// set from it.
+func (config *RunConfig) SetDefaultFromSchema() {
+	if config.HTTPPort == 0 {
+		config.HTTPPort = DefaultDaprHTTPPort
+	}
+	if config.GRPCPort == 0 {
+		config.GRPCPort = DefaultDaprGRPCPort
+	}
+	if config.MetricsPort == 0 {
+		config.MetricsPort = DefaultMetricsPort
+	}
+}
+
+// Validate checks that config has enough information to start daprd and the
+// app.
+func (config *RunConfig) Validate() error {
+	if config.AppID == "" {
+		return fmt.Errorf("app-id is required")
+	}
+	return nil
+}
+
+// GetDaprCommand builds the daprd invocation for config.
+func GetDaprCommand(config *RunConfig) (*exec.Cmd, error) {
+	args := []string{
+		"--app-id", config.AppID,
+		"--dapr-http-port", fmt.Sprintf("%d", config.HTTPPort),
+		"--dapr-grpc-port", fmt.Sprintf("%d", config.GRPCPort),
+		"--metrics-port", fmt.Sprintf("%d", config.MetricsPort),
+	}
+	return exec.Command("daprd", args...), nil
+}
+
+// GetAppCommand builds the app invocation for config, or nil if `dapr run`
+// was given no app command to run alongside daprd.
+func GetAppCommand(config *RunConfig) *exec.Cmd {
+	if len(config.Command) == 0 {
+		return nil
+	}
+	cmd := exec.Command(config.Command[0], config.Command[1:]...)
+	cmd.Env = config.AppEnv
+	return cmd
+}
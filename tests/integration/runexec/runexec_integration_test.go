@@ -0,0 +1,158 @@
+//go:build integration
+// +build integration
+
+package runexec_test
+
+import (
+	"embed"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dapr/cli/pkg/runexec"
+)
+
+//go:embed testdata/noop.wasm
+var testdata embed.FS
+
+// startFakeSidecar spins up a stub HTTP listener standing in for daprd, so
+// the app backends under test can be wired up with real DAPR_HTTP_PORT
+// values the same way `dapr run` would pass them.
+func startFakeSidecar(t *testing.T) (httpPort int) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	srv := &http.Server{Handler: http.NewServeMux()}
+	go srv.Serve(lis)
+	t.Cleanup(func() { srv.Close() })
+
+	return lis.Addr().(*net.TCPAddr).Port
+}
+
+func writeFixture(t *testing.T, name string) string {
+	t.Helper()
+
+	data, err := testdata.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// drain logs r until EOF and signals wg.Done, so callers can join it before
+// the subtest returns -- logging from an orphaned goroutine after the test
+// has completed panics the whole binary.
+func drain(t *testing.T, wg *sync.WaitGroup, label string, r interface{ Read([]byte) (int, error) }) {
+	defer wg.Done()
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			t.Logf("%s: %s", label, buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// TestRunnableCmdBackends exercises Start/Wait/Kill/Pid/Running/HasProcess
+// against both RunnableCmd implementations to make sure they behave
+// consistently from the caller's point of view, closing the gap where only
+// the exec backend had any automated coverage.
+func TestRunnableCmdBackends(t *testing.T) {
+	httpPort := startFakeSidecar(t)
+	env := []string{"DAPR_HTTP_PORT=" + strconv.Itoa(httpPort)}
+
+	tests := []struct {
+		name    string
+		newCmd  func(t *testing.T) runexec.RunnableCmd
+		wantPid bool
+	}{
+		{
+			name: "exec",
+			newCmd: func(t *testing.T) runexec.RunnableCmd {
+				return &runexec.RunnableExecCmd{Cmd: exec.Command("sh", "-c", "exit 0")}
+			},
+			wantPid: true,
+		},
+		{
+			name: "wasm",
+			newCmd: func(t *testing.T) runexec.RunnableCmd {
+				cmd, err := runexec.NewWasmCmd(writeFixture(t, "noop.wasm"), nil, env, nil, false)
+				require.NoError(t, err)
+				return cmd
+			},
+			wantPid: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := tc.newCmd(t)
+
+			stdout, err := cmd.StdoutPipe()
+			require.NoError(t, err)
+			stderr, err := cmd.StderrPipe()
+			require.NoError(t, err)
+
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go drain(t, &wg, "stdout", stdout)
+			go drain(t, &wg, "stderr", stderr)
+
+			require.NoError(t, cmd.Start())
+			assert.True(t, cmd.Running(), "Running() should be true immediately after Start")
+
+			require.NoError(t, cmd.Wait())
+			wg.Wait()
+
+			assert.Equal(t, tc.wantPid, cmd.Pid() >= 0)
+			assert.Equal(t, tc.wantPid, cmd.HasProcess())
+			assert.False(t, cmd.Running())
+			assert.NoError(t, cmd.Kill())
+		})
+	}
+}
+
+// TestKillWhileRunning exercises the exec backend's Windows-safe kill path
+// (taskkill on Windows, SIGINT twice elsewhere, see killProcess) against a
+// command that ignores a single SIGINT, making sure Kill still brings it
+// down instead of leaving it running. The wasm backend has no equivalent
+// case here: the noop.wasm fixture returns immediately, and there's nothing
+// long-running in it for Kill to interrupt.
+func TestKillWhileRunning(t *testing.T) {
+	cmd := &runexec.RunnableExecCmd{Cmd: exec.Command("sh", "-c", "trap '' INT; sleep 60")}
+
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	stderr, err := cmd.StderrPipe()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go drain(t, &wg, "stdout", stdout)
+	go drain(t, &wg, "stderr", stderr)
+
+	require.NoError(t, cmd.Start())
+	assert.True(t, cmd.Running())
+
+	require.NoError(t, cmd.Kill())
+	require.NoError(t, cmd.Wait())
+	wg.Wait()
+
+	assert.False(t, cmd.Running())
+}